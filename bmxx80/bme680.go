@@ -5,11 +5,29 @@
 package bmxx80
 
 import (
+	"errors"
+	"time"
+
 	"periph.io/x/conn/v3/physic"
 )
 
-// While the BME68x is a TPHG (temperature, pressure, humidity, gas) sensor, this implementation only supports TPH
-// This also is limited to I2C support as SPI requires additional complexity in the form of page management
+// While the BME68x is a TPHG (temperature, pressure, humidity, gas) sensor, this implementation only supports TPHG
+// on the I2C bus, as SPI requires additional complexity in the form of page management
+//
+// SenseTemperature/SensePressure/SenseHumidity and the per-channel
+// raw/compensate split below apply only to the BME68x family. bme280.go's
+// sense280 reads TPH as a single burst and has not been split the same way;
+// do so there too if/when that file is extracted into this package.
+
+// bme68xRegCtrlMeas is the ctrl_meas register: osrs_t[7:5], osrs_p[4:2], mode[1:0].
+const bme68xRegCtrlMeas = 0x74
+
+// tFineMaxAge bounds how long a cached t_fine value from a temperature read
+// may be reused to compensate a later, channel-only pressure or humidity
+// read. Pressure/humidity compensation needs a temperature that is
+// contemporaneous with the raw ADC value; reusing one from minutes ago
+// silently produces a biased reading.
+const tFineMaxAge = 200 * time.Millisecond
 
 // sense68x reads the device's registers for bme680/bme688.
 //
@@ -31,35 +49,199 @@ func (d *Dev) sense68x(e *physic.Env) error {
 	pRaw := int32(buf[0])<<12 | int32(buf[1])<<4 | int32(buf[2])>>4
 	tRaw := int32(buf[3])<<12 | int32(buf[4])<<4 | int32(buf[5])>>4
 
-	t, tFine := d.cal68x.compensateTempInt(tRaw)
-	// Convert CentiCelsius to Kelvin.
-	e.Temperature = physic.Temperature(t)*10*physic.MilliCelsius + physic.ZeroCelsius
+	t, tFine := d.cal68x.compensateTemp68x(tRaw)
+	e.Temperature = t
+	d.lastTemperature = t
+	d.cacheTFine68x(tFine)
 
 	if d.opts.Pressure != Off {
-		p := d.cal68x.compensatePressureFloat(pRaw, tFine)
-		// It has 8 bits of fractional Pascal.
-		e.Pressure = physic.Pressure(p*256) * 15625 * physic.MicroPascal / 4
+		e.Pressure = d.cal68x.compensatePressure68x(pRaw, tFine)
 	}
 
 	if d.opts.Humidity != Off {
 		// This value is 16 bits as per doc.
 		hRaw := int32(buf[6])<<8 | int32(buf[7])
-		h := physic.RelativeHumidity(d.cal68x.compensateHumidityInt(hRaw, tFine))
-		// Convert base 1024 to base 1000.
-		e.Humidity = h * 10000 / 1024 * physic.MicroRH
+		e.Humidity = d.cal68x.compensateHumidity68x(hRaw, tFine)
+	}
+
+	if d.opts.Heater != (GasHeaterProfile{}) {
+		r, err := d.senseGas68x()
+		if err != nil {
+			return err
+		}
+		d.lastGas = r
 	}
 
 	return nil
 }
 
+// cacheTFine68x records a freshly computed t_fine value along with the time
+// it was produced, for reuse by a subsequent channel-only pressure or
+// humidity read.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) cacheTFine68x(tFine int32) {
+	d.tFine = tFine
+	d.tFineAt = time.Now()
+}
+
+// freshTFine68x returns the cached t_fine value if it is younger than
+// tFineMaxAge, performing a fresh temperature read otherwise.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) freshTFine68x() (int32, error) {
+	if !d.tFineAt.IsZero() && time.Since(d.tFineAt) < tFineMaxAge {
+		return d.tFine, nil
+	}
+	if _, err := d.readTempADC68x(); err != nil {
+		return 0, err
+	}
+	return d.tFine, nil
+}
+
+// readTempADC68x reads and compensates only the temperature channel,
+// caching t_fine for SensePressure/SenseHumidity.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) readTempADC68x() (physic.Temperature, error) {
+	buf := [3]byte{}
+	if err := d.readReg(0x22, buf[:]); err != nil {
+		return 0, err
+	}
+	tRaw := int32(buf[0])<<12 | int32(buf[1])<<4 | int32(buf[2])>>4
+	t, tFine := d.cal68x.compensateTemp68x(tRaw)
+	d.lastTemperature = t
+	d.cacheTFine68x(tFine)
+	return t, nil
+}
+
+// readPressureADC68x reads and compensates only the pressure channel,
+// reusing a fresh cached t_fine or transparently reading the temperature
+// registers if none is available.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) readPressureADC68x() (physic.Pressure, error) {
+	tFine, err := d.freshTFine68x()
+	if err != nil {
+		return 0, err
+	}
+	buf := [3]byte{}
+	if err := d.readReg(0x1F, buf[:]); err != nil {
+		return 0, err
+	}
+	pRaw := int32(buf[0])<<12 | int32(buf[1])<<4 | int32(buf[2])>>4
+	return d.cal68x.compensatePressure68x(pRaw, tFine), nil
+}
+
+// readHumidityADC68x reads and compensates only the humidity channel,
+// reusing a fresh cached t_fine or transparently reading the temperature
+// registers if none is available.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) readHumidityADC68x() (physic.RelativeHumidity, error) {
+	tFine, err := d.freshTFine68x()
+	if err != nil {
+		return 0, err
+	}
+	buf := [2]byte{}
+	if err := d.readReg(0x25, buf[:]); err != nil {
+		return 0, err
+	}
+	hRaw := int32(buf[0])<<8 | int32(buf[1])
+	return d.cal68x.compensateHumidity68x(hRaw, tFine), nil
+}
+
+// compensateTemp68x converts a raw 20 bit ADC reading into a physic.Temperature,
+// returning the t_fine value pressure/humidity compensation needs alongside it.
+func (c *calibration68x) compensateTemp68x(raw int32) (physic.Temperature, int32) {
+	t, tFine := c.compensateTempInt(raw)
+	// Convert CentiCelsius to Kelvin.
+	return physic.Temperature(t)*10*physic.MilliCelsius + physic.ZeroCelsius, tFine
+}
+
+// compensatePressure68x converts a raw 20 bit ADC reading into a physic.Pressure.
+func (c *calibration68x) compensatePressure68x(raw, tFine int32) physic.Pressure {
+	p := c.compensatePressureFloat(raw, tFine)
+	// It has 8 bits of fractional Pascal.
+	return physic.Pressure(p*256) * 15625 * physic.MicroPascal / 4
+}
+
+// compensateHumidity68x converts a raw 16 bit ADC reading into a physic.RelativeHumidity.
+func (c *calibration68x) compensateHumidity68x(raw, tFine int32) physic.RelativeHumidity {
+	h := physic.RelativeHumidity(c.compensateHumidityInt(raw, tFine))
+	// Convert base 1024 to base 1000.
+	return h * 10000 / 1024 * physic.MicroRH
+}
+
+// triggerForced puts the sensor in forced mode, which starts a single TPH(G)
+// measurement cycle, and waits for it to complete.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) triggerForced() error {
+	v := [1]byte{}
+	if err := d.readReg(bme68xRegCtrlMeas, v[:]); err != nil {
+		return err
+	}
+	// mode (bits 1:0) = forced (0b01); preserve the oversampling bits already
+	// configured in ctrl_meas.
+	mode := (v[0] &^ 0x03) | 0x01
+	if err := d.writeCommands([]byte{bme68xRegCtrlMeas, mode}); err != nil {
+		return err
+	}
+	for i := 0; i < 100; i++ {
+		idle, err := d.isIdle68x()
+		if err != nil {
+			return err
+		}
+		if idle {
+			return nil
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return errors.New("bmxx80: timeout waiting for forced-mode measurement to complete")
+}
+
+// SenseTemperature triggers a forced-mode measurement cycle and reads back
+// only the temperature channel.
+func (d *Dev) SenseTemperature() (physic.Temperature, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.triggerForced(); err != nil {
+		return 0, err
+	}
+	return d.readTempADC68x()
+}
+
+// SensePressure triggers a forced-mode measurement cycle and reads back only
+// the pressure channel, reading temperature first if no fresh t_fine is cached.
+func (d *Dev) SensePressure() (physic.Pressure, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.triggerForced(); err != nil {
+		return 0, err
+	}
+	return d.readPressureADC68x()
+}
+
+// SenseHumidity triggers a forced-mode measurement cycle and reads back only
+// the humidity channel, reading temperature first if no fresh t_fine is cached.
+func (d *Dev) SenseHumidity() (physic.RelativeHumidity, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.triggerForced(); err != nil {
+		return 0, err
+	}
+	return d.readHumidityADC68x()
+}
+
 func (d *Dev) isIdle68x() (bool, error) {
 	// status
 	v := [1]byte{}
 	if err := d.readReg(0x1D, v[:]); err != nil {
 		return false, err
 	}
-	// Make sure bit 5 (TPH) and 6(G) is cleared.
-	return v[0]&60 == 0, nil
+	// Make sure bit 5 (measuring, TPH) and bit 6 (gas_measuring, G) are cleared.
+	return v[0]&0x60 == 0, nil
 }
 
 // newCalibration parses calibration data from both buffers.
@@ -85,6 +267,12 @@ func newCalibration68x(tph, h []byte) (c calibration68x) {
 	c.h6 = h[29-23]
 	c.h7 = int8(h[30-23])
 
+	// Gas heater calibration, read from the same block as the humidity and
+	// pressure/temperature trim values.
+	c.g1 = int8(h[45-23])
+	c.g2 = int16(h[43-23]) | int16(h[44-23])<<8
+	c.g3 = int8(h[46-23])
+
 	return c
 }
 
@@ -99,6 +287,14 @@ type calibration68x struct {
 	h1, h2             uint16
 	h3, h4, h5, h7     int8
 	h6                 uint8
+	g1, g3             int8
+	g2                 int16
+	// resHeatRange, resHeatVal and rangeSwErr are not part of the tph/h
+	// calibration blocks; they live in registers 0x02, 0x00 and 0x04
+	// respectively and are populated by readGasCalibration68x.
+	resHeatRange uint8
+	resHeatVal   int8
+	rangeSwErr   int8
 }
 
 // compensateTempInt returns temperature in °C, resolution is 0.01 °C.
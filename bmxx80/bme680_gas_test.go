@@ -0,0 +1,68 @@
+// Copyright 2024 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bmxx80
+
+import (
+	"testing"
+
+	"periph.io/x/conn/v3/physic"
+)
+
+func TestCalcGasWait(t *testing.T) {
+	tests := []struct {
+		ms   int64
+		want byte
+	}{
+		{0, 0},
+		{59, 59},
+		{63, 63},
+		// 64 overflows the 6 bit value field, bumping the multiplier to 4x.
+		{64, 1*64 + 16},
+		{100, 1*64 + 25},
+		{0xfc0, 0xff},
+		{0xfc0 + 1, 0xff},
+	}
+	for _, tt := range tests {
+		if got := calcGasWait(tt.ms); got != tt.want {
+			t.Errorf("calcGasWait(%d) = %#02x, want %#02x", tt.ms, got, tt.want)
+		}
+	}
+}
+
+func TestCalcResHeat(t *testing.T) {
+	c := &calibration68x{g1: -30, g2: -8000, g3: 30, resHeatRange: 2, resHeatVal: 50}
+	got := c.calcResHeat(physic.ZeroCelsius+300*physic.Celsius, physic.ZeroCelsius+25*physic.Celsius)
+	if got == 0 || got == 255 {
+		t.Errorf("calcResHeat() = %d, want a mid-range value, not a saturated one", got)
+	}
+}
+
+func TestCalcResHeatClampsTarget(t *testing.T) {
+	c := &calibration68x{g1: -30, g2: -8000, g3: 30, resHeatRange: 2, resHeatVal: 50}
+	at400 := c.calcResHeat(physic.ZeroCelsius+400*physic.Celsius, physic.ZeroCelsius+25*physic.Celsius)
+	above400 := c.calcResHeat(physic.ZeroCelsius+500*physic.Celsius, physic.ZeroCelsius+25*physic.Celsius)
+	if at400 != above400 {
+		t.Errorf("calcResHeat() target above 400°C was not clamped: got %d, want %d", above400, at400)
+	}
+}
+
+func TestCompensateGas(t *testing.T) {
+	c := &calibration68x{rangeSwErr: 4}
+	got := c.compensateGas(512, 0)
+	if got <= 0 {
+		t.Errorf("compensateGas() = %v, want a positive resistance", got)
+	}
+}
+
+func TestCompensateGasHigherRangeLowerResistance(t *testing.T) {
+	// Per the lookup tables, a higher gas_range_r index divides down to a
+	// smaller resistance for the same raw ADC count.
+	c := &calibration68x{rangeSwErr: 4}
+	low := c.compensateGas(512, 0)
+	high := c.compensateGas(512, 10)
+	if high >= low {
+		t.Errorf("compensateGas() range 10 = %v, want it smaller than range 0 = %v", high, low)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bmxx80
+
+import "testing"
+
+// testCalibration68x returns a set of plausible factory calibration
+// constants, representative of the values a real BME680 reports, so the
+// compensate functions below exercise realistic code paths rather than
+// all-zero coefficients.
+func testCalibration68x() *calibration68x {
+	return &calibration68x{
+		t1: 26438, t2: 26304, t3: 3,
+		p1: 35344, p2: -10562, p3: 88, p4: 6457, p5: -101, p6: 30, p7: 33, p8: -6312, p9: -2272, p10: 30,
+		h1: 676, h2: 1029, h3: 0, h4: 45, h5: 20, h6: 120, h7: -30,
+	}
+}
+
+func TestCompensateTemp68x(t *testing.T) {
+	c := testCalibration68x()
+	temp, tFine := c.compensateTemp68x(500000)
+	if celsius(temp) < 15 || celsius(temp) > 35 {
+		t.Errorf("compensateTemp68x() = %v (%.2f°C), want a plausible room temperature", temp, celsius(temp))
+	}
+	if tFine == 0 {
+		t.Error("compensateTemp68x() returned a zero t_fine")
+	}
+}
+
+func TestCompensatePressure68x(t *testing.T) {
+	c := testCalibration68x()
+	_, tFine := c.compensateTemp68x(500000)
+	p := c.compensatePressure68x(415706, tFine)
+	// A raw ADC count in the typical sea-level range should compensate to
+	// somewhere near atmospheric pressure; this guards against a sign error
+	// or unit-conversion regression, not bit-exact accuracy.
+	if pascals(p) < 50000 || pascals(p) > 150000 {
+		t.Errorf("compensatePressure68x() = %v Pa, want a plausible atmospheric pressure", pascals(p))
+	}
+}
+
+func TestCompensateHumidity68x(t *testing.T) {
+	c := testCalibration68x()
+	_, tFine := c.compensateTemp68x(500000)
+	h := c.compensateHumidity68x(20000, tFine)
+	if percentRH(h) < 0 || percentRH(h) > 100 {
+		t.Errorf("compensateHumidity68x() = %v%%RH, want a value within [0, 100]", percentRH(h))
+	}
+}
+
+func TestCompensateTemp68xMonotonic(t *testing.T) {
+	c := testCalibration68x()
+	low, _ := c.compensateTemp68x(400000)
+	high, _ := c.compensateTemp68x(420000)
+	if high <= low {
+		t.Errorf("compensateTemp68x() is not monotonic in the raw ADC count: low=%v high=%v", low, high)
+	}
+}
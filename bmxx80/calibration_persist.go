@@ -0,0 +1,129 @@
+// Copyright 2024 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bmxx80
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// Calibration280 and Calibration180 would be the BME280/BMP280 and BMP180
+// equivalents of Calibration68x below, but bme280.go/bmp180.go are not part
+// of this source tree; add them alongside those files, following the same
+// chip-ID+checksum header and encode/decode split used here.
+
+// calibrationMagic identifies a blob produced by MarshalCalibration, so
+// UnmarshalCalibration can reject data from another format before trying to
+// interpret it.
+const calibrationMagic = 0xB6
+
+// Calibration68x is the exported, serializable form of a BME680/BME688's
+// factory NVM calibration constants. These never change over the life of
+// the part, so applications can cache them to disk via MarshalCalibration
+// and skip the calibration-block I2C reads on a later boot.
+type Calibration68x struct {
+	T1, P1                 uint16
+	T2, P2, P4, P5, P8, P9 int16
+	T3, P3, P6, P7         int8
+	P10                    uint8
+	H1, H2                 uint16
+	H3, H4, H5, H7         int8
+	H6                     uint8
+	G1, G3                 int8
+	G2                     int16
+	ResHeatRange           uint8
+	ResHeatVal             int8
+	RangeSwErr             int8
+}
+
+func (c calibration68x) export() Calibration68x {
+	return Calibration68x{
+		T1: c.t1, T2: c.t2, T3: c.t3,
+		P1: c.p1, P2: c.p2, P3: c.p3, P4: c.p4, P5: c.p5, P6: c.p6, P7: c.p7, P8: c.p8, P9: c.p9, P10: c.p10,
+		H1: c.h1, H2: c.h2, H3: c.h3, H4: c.h4, H5: c.h5, H6: c.h6, H7: c.h7,
+		G1: c.g1, G2: c.g2, G3: c.g3,
+		ResHeatRange: c.resHeatRange, ResHeatVal: c.resHeatVal, RangeSwErr: c.rangeSwErr,
+	}
+}
+
+func (c Calibration68x) imported() calibration68x {
+	return calibration68x{
+		t1: c.T1, t2: c.T2, t3: c.T3,
+		p1: c.P1, p2: c.P2, p3: c.P3, p4: c.P4, p5: c.P5, p6: c.P6, p7: c.P7, p8: c.P8, p9: c.P9, p10: c.P10,
+		h1: c.H1, h2: c.H2, h3: c.H3, h4: c.H4, h5: c.H5, h6: c.H6, h7: c.H7,
+		g1: c.G1, g2: c.G2, g3: c.G3,
+		resHeatRange: c.ResHeatRange, resHeatVal: c.ResHeatVal, rangeSwErr: c.RangeSwErr,
+	}
+}
+
+// marshalCalibration68x serializes c behind a chip-ID and checksum header.
+// It is split out from MarshalCalibration so it can be exercised without a
+// live Dev.
+func marshalCalibration68x(chipID byte, c Calibration68x) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte(calibrationMagic)
+	buf.WriteByte(chipID)
+	if err := binary.Write(buf, binary.BigEndian, c); err != nil {
+		return nil, err
+	}
+	sum := crc32.ChecksumIEEE(buf.Bytes())
+	if err := binary.Write(buf, binary.BigEndian, sum); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalCalibration68x is the inverse of marshalCalibration68x. It
+// rejects b if its checksum doesn't match, its header is unrecognized, or
+// it was captured from a chip other than chipID.
+func unmarshalCalibration68x(chipID byte, b []byte) (Calibration68x, error) {
+	const headerLen, checksumLen = 2, 4
+	if len(b) < headerLen+checksumLen {
+		return Calibration68x{}, errors.New("bmxx80: calibration blob too short")
+	}
+	payload, sum := b[:len(b)-checksumLen], b[len(b)-checksumLen:]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(sum) {
+		return Calibration68x{}, errors.New("bmxx80: calibration blob failed its checksum")
+	}
+	if payload[0] != calibrationMagic {
+		return Calibration68x{}, errors.New("bmxx80: calibration blob has an unrecognized header")
+	}
+	if payload[1] != chipID {
+		return Calibration68x{}, errors.New("bmxx80: calibration blob was captured from a different chip")
+	}
+	var c Calibration68x
+	if err := binary.Read(bytes.NewReader(payload[headerLen:]), binary.BigEndian, &c); err != nil {
+		return Calibration68x{}, err
+	}
+	return c, nil
+}
+
+// MarshalCalibration serializes d's factory NVM calibration constants behind
+// a chip-ID and checksum header, so they can be cached to disk and fed back
+// via UnmarshalCalibration on a later boot instead of re-reading the
+// calibration block over the bus.
+func (d *Dev) MarshalCalibration() ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return marshalCalibration68x(d.chipID, d.cal68x.export())
+}
+
+// UnmarshalCalibration restores calibration constants previously produced by
+// MarshalCalibration. The blob is rejected, and d left unchanged, if its
+// checksum doesn't match or it was captured from a different chip; callers
+// should fall back to re-reading the calibration block from the sensor in
+// that case.
+func (d *Dev) UnmarshalCalibration(b []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c, err := unmarshalCalibration68x(d.chipID, b)
+	if err != nil {
+		return err
+	}
+	d.cal68x = c.imported()
+	return nil
+}
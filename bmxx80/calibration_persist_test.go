@@ -0,0 +1,56 @@
+// Copyright 2024 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bmxx80
+
+import "testing"
+
+func TestCalibration68xRoundTrip(t *testing.T) {
+	c := Calibration68x{
+		T1: 26438, T2: 26304, T3: 3,
+		P1: 35344, P2: -10562, P3: 88, P4: 6457, P5: -101, P6: 30, P7: 33, P8: -6312, P9: -2272, P10: 30,
+		H1: 676, H2: 1029, H3: 0, H4: 45, H5: 20, H6: 120, H7: -30,
+		G1: -30, G2: -8000, G3: 30,
+		ResHeatRange: 2, ResHeatVal: 50, RangeSwErr: 4,
+	}
+
+	b, err := marshalCalibration68x(0x61, c)
+	if err != nil {
+		t.Fatalf("marshalCalibration68x: %v", err)
+	}
+	got, err := unmarshalCalibration68x(0x61, b)
+	if err != nil {
+		t.Fatalf("unmarshalCalibration68x: %v", err)
+	}
+	if got != c {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", got, c)
+	}
+}
+
+func TestCalibration68xRejectsWrongChip(t *testing.T) {
+	b, err := marshalCalibration68x(0x61, Calibration68x{T1: 1})
+	if err != nil {
+		t.Fatalf("marshalCalibration68x: %v", err)
+	}
+	if _, err := unmarshalCalibration68x(0x62, b); err == nil {
+		t.Fatal("unmarshalCalibration68x: expected an error for a mismatched chip ID, got nil")
+	}
+}
+
+func TestCalibration68xRejectsCorruptBlob(t *testing.T) {
+	b, err := marshalCalibration68x(0x61, Calibration68x{T1: 1})
+	if err != nil {
+		t.Fatalf("marshalCalibration68x: %v", err)
+	}
+	b[len(b)/2] ^= 0xff
+	if _, err := unmarshalCalibration68x(0x61, b); err == nil {
+		t.Fatal("unmarshalCalibration68x: expected an error for a corrupted blob, got nil")
+	}
+}
+
+func TestCalibration68xRejectsShortBlob(t *testing.T) {
+	if _, err := unmarshalCalibration68x(0x61, []byte{1, 2, 3}); err == nil {
+		t.Fatal("unmarshalCalibration68x: expected an error for a too-short blob, got nil")
+	}
+}
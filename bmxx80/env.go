@@ -0,0 +1,69 @@
+// Copyright 2024 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bmxx80
+
+import (
+	"math"
+
+	"periph.io/x/conn/v3/physic"
+)
+
+// DewPoint returns the dew point temperature for a sensed environment, using
+// the Magnus formula. It is only accurate for humidity readings above
+// freezing; accuracy degrades below 0°C and at relative humidity extremes.
+func DewPoint(env physic.Env) physic.Temperature {
+	t := celsius(env.Temperature)
+	rh := percentRH(env.Humidity)
+	gamma := math.Log(rh/100) + (17.625*t)/(243.04+t)
+	return fromCelsius(243.04 * gamma / (17.625 - gamma))
+}
+
+// Altitude estimates the altitude in meters above seaLevel given a sensed
+// pressure p, using the international barometric formula. It assumes
+// standard atmospheric conditions and is only as accurate as seaLevel.
+func Altitude(p, seaLevel physic.Pressure) float64 {
+	return 44330 * (1 - math.Pow(pascals(p)/pascals(seaLevel), 1/5.255))
+}
+
+// SeaLevelPressure reduces a sensed pressure p, taken at altitudeMeters
+// above sea level, to the equivalent sea-level pressure. It is the inverse
+// of Altitude.
+func SeaLevelPressure(p physic.Pressure, altitudeMeters float64) physic.Pressure {
+	return fromPascals(pascals(p) / math.Pow(1-altitudeMeters/44330, 5.255))
+}
+
+// AbsoluteHumidity returns the mass of water vapor per cubic meter of air,
+// in g/m³, for a sensed environment.
+func AbsoluteHumidity(env physic.Env) float64 {
+	t := celsius(env.Temperature)
+	rh := percentRH(env.Humidity)
+	return 216.7 * (rh / 100 * 6.112 * math.Exp(17.62*t/(243.12+t))) / (273.15 + t)
+}
+
+// Altitude returns the altitude estimated from env.Pressure, using
+// Opts.SeaLevelPressure as the reference pressure.
+func (d *Dev) Altitude(env physic.Env) float64 {
+	return Altitude(env.Pressure, d.opts.SeaLevelPressure)
+}
+
+func celsius(t physic.Temperature) float64 {
+	return float64(t-physic.ZeroCelsius) / float64(physic.Kelvin)
+}
+
+func fromCelsius(c float64) physic.Temperature {
+	return physic.Temperature(c*float64(physic.Kelvin)) + physic.ZeroCelsius
+}
+
+func percentRH(h physic.RelativeHumidity) float64 {
+	return float64(h) / float64(physic.PercentRH)
+}
+
+func pascals(p physic.Pressure) float64 {
+	return float64(p) / float64(physic.Pascal)
+}
+
+func fromPascals(pa float64) physic.Pressure {
+	return physic.Pressure(pa * float64(physic.Pascal))
+}
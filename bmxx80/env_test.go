@@ -0,0 +1,70 @@
+// Copyright 2024 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bmxx80
+
+import (
+	"math"
+	"testing"
+
+	"periph.io/x/conn/v3/physic"
+)
+
+func approxTemp(t physic.Temperature, wantC, tolC float64) bool {
+	return math.Abs(celsius(t)-wantC) <= tolC
+}
+
+func TestDewPoint(t *testing.T) {
+	// 25°C at 50% RH has a well known dew point of ~13.9°C.
+	env := physic.Env{
+		Temperature: fromCelsius(25),
+		Humidity:    physic.RelativeHumidity(50 * physic.PercentRH),
+	}
+	if got := DewPoint(env); !approxTemp(got, 13.9, 0.2) {
+		t.Errorf("DewPoint() = %v (%.2f°C), want ~13.9°C", got, celsius(got))
+	}
+}
+
+func TestDewPointAtSaturation(t *testing.T) {
+	// At 100% RH the dew point equals the air temperature.
+	env := physic.Env{
+		Temperature: fromCelsius(20),
+		Humidity:    physic.RelativeHumidity(100 * physic.PercentRH),
+	}
+	if got := DewPoint(env); !approxTemp(got, 20, 0.1) {
+		t.Errorf("DewPoint() = %v (%.2f°C), want ~20°C", got, celsius(got))
+	}
+}
+
+func TestAltitudeRoundTrip(t *testing.T) {
+	seaLevel := physic.Pressure(101325 * physic.Pascal)
+	p := fromPascals(89874) // ~1000m per the standard atmosphere.
+	alt := Altitude(p, seaLevel)
+	if math.Abs(alt-1000) > 15 {
+		t.Errorf("Altitude() = %v, want ~1000m", alt)
+	}
+
+	back := SeaLevelPressure(p, alt)
+	if math.Abs(pascals(back)-pascals(seaLevel)) > 50 {
+		t.Errorf("SeaLevelPressure() = %v Pa, want ~%v Pa", pascals(back), pascals(seaLevel))
+	}
+}
+
+func TestAltitudeZero(t *testing.T) {
+	seaLevel := physic.Pressure(101325 * physic.Pascal)
+	if got := Altitude(seaLevel, seaLevel); math.Abs(got) > 1e-6 {
+		t.Errorf("Altitude() at sea level = %v, want 0", got)
+	}
+}
+
+func TestAbsoluteHumidity(t *testing.T) {
+	// 25°C at 50% RH has an absolute humidity of ~11.5 g/m³.
+	env := physic.Env{
+		Temperature: fromCelsius(25),
+		Humidity:    physic.RelativeHumidity(50 * physic.PercentRH),
+	}
+	if got := AbsoluteHumidity(env); math.Abs(got-11.5) > 0.3 {
+		t.Errorf("AbsoluteHumidity() = %v, want ~11.5 g/m³", got)
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright 2024 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bmxx80
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"periph.io/x/conn/v3/physic"
+)
+
+// SensedEnv pairs an environmental sample with the time it was taken, for
+// consumers of a streaming Subscription.
+type SensedEnv struct {
+	physic.Env
+	Timestamp time.Time
+}
+
+// BackpressurePolicy controls what a streaming subscriber's buffer does when
+// its consumer falls behind the hardware poll cadence.
+type BackpressurePolicy int
+
+const (
+	// DropOldest discards the oldest buffered sample to make room for the
+	// new one. This is the default, since a stale environmental reading is
+	// rarely more useful than a fresh one.
+	DropOldest BackpressurePolicy = iota
+	// Block guarantees every sample is eventually delivered, queuing them
+	// for this subscriber without bound until its consumer catches up. A
+	// slow Block consumer only ever grows its own queue; it never applies
+	// backpressure to the shared poll loop or to other subscribers.
+	Block
+)
+
+// StreamOptions configures a subscription created by Dev.Stream.
+type StreamOptions struct {
+	// Rate is how often this subscriber wants a new sample. The background
+	// poller runs at the fastest Rate requested across all active
+	// subscribers, so a slower subscriber simply receives (and, under
+	// DropOldest, drops) the intermediate samples.
+	Rate time.Duration
+	// Depth is the number of buffered samples before Policy kicks in.
+	// Defaults to 1 if zero. Unused under Block, whose queue is unbounded.
+	Depth int
+	// Policy controls backpressure behavior. Defaults to DropOldest.
+	Policy BackpressurePolicy
+}
+
+// Subscription is a single consumer's view of the streaming subsystem
+// started by Dev.Stream. Samples arrive on C; once Close is called, any
+// sample already buffered or queued for this subscriber remains readable
+// until C drains, at which point C is closed.
+type Subscription struct {
+	C <-chan SensedEnv
+
+	d  *Dev
+	id uint64
+}
+
+// Close unsubscribes this consumer and closes C once its buffered samples
+// have been read. Once the last subscriber of a Dev closes, the background
+// polling goroutine stops and the sensor is halted.
+func (s *Subscription) Close() error {
+	return s.d.unsubscribe(s.id)
+}
+
+// streamState is the background sampling goroutine shared by all of a Dev's
+// subscribers, so N subscribers cause a single hardware poll cadence rather
+// than N independent ones. subs, and every subscriber's channel lifecycle,
+// are only ever mutated with mu held, so a subscriber can never be
+// delivered to and closed at the same time.
+type streamState struct {
+	mu       sync.Mutex
+	subs     map[uint64]*subscriber
+	nextID   uint64
+	stopC    chan struct{}
+	doneC    chan struct{}
+	stopOnce sync.Once
+}
+
+// stop terminates the poll loop, if it hasn't already, and waits for it to
+// exit. It is safe to call concurrently and more than once.
+func (s *streamState) stop() {
+	s.stopOnce.Do(func() { close(s.stopC) })
+	<-s.doneC
+}
+
+// subscriber is a single Stream subscriber. DropOldest sends directly on c
+// with a non-blocking ring-buffer policy, so it is always fast enough to do
+// under streamState.mu. Block instead hands samples to an unbounded queue
+// consumed by a dedicated worker goroutine that is the sole owner of c, so
+// a stalled Block consumer can never hold up streamState.mu, the poll
+// loop, or delivery to other subscribers.
+type subscriber struct {
+	opts StreamOptions
+	c    chan SensedEnv
+
+	// The following are only used when opts.Policy == Block.
+	qMu     sync.Mutex
+	qCond   *sync.Cond
+	queue   []SensedEnv
+	closing bool
+}
+
+func newSubscriber(opts StreamOptions, c chan SensedEnv) *subscriber {
+	sub := &subscriber{opts: opts, c: c}
+	if opts.Policy == Block {
+		sub.qCond = sync.NewCond(&sub.qMu)
+		go sub.blockWorker()
+	}
+	return sub
+}
+
+// blockWorker is the sole goroutine allowed to send on or close c for a
+// Block subscriber, which makes doing both safe without any lock on c
+// itself: there is never a second goroutine that could race a close
+// against a send.
+func (sub *subscriber) blockWorker() {
+	for {
+		sub.qMu.Lock()
+		for len(sub.queue) == 0 && !sub.closing {
+			sub.qCond.Wait()
+		}
+		if len(sub.queue) == 0 {
+			sub.qMu.Unlock()
+			close(sub.c)
+			return
+		}
+		sample := sub.queue[0]
+		sub.queue = sub.queue[1:]
+		sub.qMu.Unlock()
+
+		sub.c <- sample
+	}
+}
+
+// enqueue appends a sample for a Block subscriber's worker to deliver. It
+// never blocks the caller (streamLoop).
+func (sub *subscriber) enqueue(sample SensedEnv) {
+	sub.qMu.Lock()
+	sub.queue = append(sub.queue, sample)
+	sub.qMu.Unlock()
+	sub.qCond.Signal()
+}
+
+// requestClose tells a Block subscriber's worker to close c once it has
+// drained any samples already queued. It never blocks the caller.
+func (sub *subscriber) requestClose() {
+	sub.qMu.Lock()
+	sub.closing = true
+	sub.qMu.Unlock()
+	sub.qCond.Signal()
+}
+
+// Stream starts (or joins) the background sampling goroutine and returns a
+// Subscription delivering one SensedEnv roughly every opts.Rate.
+func (d *Dev) Stream(opts StreamOptions) (*Subscription, error) {
+	if opts.Rate <= 0 {
+		return nil, errors.New("bmxx80: Stream requires a positive Rate")
+	}
+	if opts.Depth <= 0 {
+		opts.Depth = 1
+	}
+
+	d.streamMu.Lock()
+	defer d.streamMu.Unlock()
+	needStart := d.stream == nil
+	if needStart {
+		d.stream = &streamState{subs: map[uint64]*subscriber{}}
+	}
+	s := d.stream
+
+	c := make(chan SensedEnv, opts.Depth)
+	sub := newSubscriber(opts, c)
+
+	s.mu.Lock()
+	id := s.nextID
+	s.nextID++
+	s.subs[id] = sub
+	s.mu.Unlock()
+
+	if needStart {
+		s.stopC = make(chan struct{})
+		s.doneC = make(chan struct{})
+		go d.streamLoop(s)
+	}
+
+	return &Subscription{C: c, d: d, id: id}, nil
+}
+
+// unsubscribe removes a subscriber and closes its channel in the same
+// streamState.mu critical section, so streamLoop's fan-out (which also
+// holds mu for its duration) can never observe a subscriber that is
+// simultaneously being closed. If it was the last subscriber, d.streamMu is
+// held across the emptiness check and the nilling of d.stream, the same
+// section Stream locks to decide whether to join an existing streamState —
+// so a concurrent Stream can never join a streamState that unsubscribe has
+// already committed to tearing down.
+func (d *Dev) unsubscribe(id uint64) error {
+	d.streamMu.Lock()
+	s := d.stream
+	if s == nil {
+		d.streamMu.Unlock()
+		return nil
+	}
+
+	s.mu.Lock()
+	sub, ok := s.subs[id]
+	delete(s.subs, id)
+	empty := len(s.subs) == 0
+	if ok {
+		closeOne(sub)
+	}
+	s.mu.Unlock()
+
+	if !empty {
+		d.streamMu.Unlock()
+		return nil
+	}
+	d.stream = nil
+	d.streamMu.Unlock()
+
+	s.stop()
+	return d.Halt()
+}
+
+// StopStreaming cancels every active subscription on d and halts the
+// sensor. The poll loop is stopped first so it can no longer deliver, then
+// every subscriber's channel is closed; any sample already buffered or
+// queued for a subscriber remains readable by a consumer ranging over it
+// until it drains, matching Subscription.Close's per-subscriber contract.
+func (d *Dev) StopStreaming() error {
+	d.streamMu.Lock()
+	s := d.stream
+	d.stream = nil
+	d.streamMu.Unlock()
+	if s == nil {
+		return d.Halt()
+	}
+
+	s.stop()
+
+	s.mu.Lock()
+	for _, sub := range s.subs {
+		closeOne(sub)
+	}
+	s.subs = nil
+	s.mu.Unlock()
+
+	return d.Halt()
+}
+
+// closeOne closes a single subscriber's channel via whichever path owns it:
+// a Block subscriber's dedicated worker, or directly for DropOldest. The
+// caller must hold streamState.mu.
+func closeOne(sub *subscriber) {
+	if sub.opts.Policy == Block {
+		sub.requestClose()
+		return
+	}
+	close(sub.c)
+}
+
+// streamLoop issues forced-mode triggers at the fastest rate requested by
+// any active subscriber and fans each sample out while holding s.mu for the
+// duration: both delivery paths (a non-blocking DropOldest send and a
+// Block enqueue) are O(1) and never block, so this never stalls
+// unsubscribe/StopStreaming or other subscribers.
+func (d *Dev) streamLoop(s *streamState) {
+	defer close(s.doneC)
+	t := time.NewTicker(s.fastestRate())
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stopC:
+			return
+		case now := <-t.C:
+			var e physic.Env
+			if err := d.Sense(&e); err != nil {
+				continue
+			}
+			sample := SensedEnv{Env: e, Timestamp: now}
+
+			s.mu.Lock()
+			for _, sub := range s.subs {
+				deliver(sub, sample)
+			}
+			rate := s.fastestRateLocked()
+			s.mu.Unlock()
+			t.Reset(rate)
+		}
+	}
+}
+
+// deliver fans a sample out to a single subscriber, applying its
+// backpressure policy. For DropOldest this is a direct, non-blocking
+// channel send; for Block it is a non-blocking queue append consumed by
+// that subscriber's dedicated worker. Neither path blocks.
+func deliver(sub *subscriber, sample SensedEnv) {
+	if sub.opts.Policy == Block {
+		sub.enqueue(sample)
+		return
+	}
+	select {
+	case sub.c <- sample:
+		return
+	default:
+	}
+	select {
+	case <-sub.c:
+	default:
+	}
+	select {
+	case sub.c <- sample:
+	default:
+	}
+}
+
+// fastestRate is fastestRateLocked without requiring the caller to already
+// hold s.mu.
+func (s *streamState) fastestRate() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fastestRateLocked()
+}
+
+// fastestRateLocked returns the fastest (smallest) Rate across all active
+// subscribers. s.mu must be held.
+func (s *streamState) fastestRateLocked() time.Duration {
+	best := time.Hour
+	for _, sub := range s.subs {
+		if sub.opts.Rate < best {
+			best = sub.opts.Rate
+		}
+	}
+	return best
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bmxx80
+
+import (
+	"testing"
+	"time"
+)
+
+// Dev itself (and therefore Stream/unsubscribe/StopStreaming, which are
+// methods on *Dev) is not part of this source tree, so these tests exercise
+// streamState/subscriber/deliver directly instead.
+
+func TestDeliverDropOldestRing(t *testing.T) {
+	sub := newSubscriber(StreamOptions{Rate: time.Millisecond, Depth: 1, Policy: DropOldest}, make(chan SensedEnv, 1))
+	first := SensedEnv{Timestamp: time.Unix(1, 0)}
+	second := SensedEnv{Timestamp: time.Unix(2, 0)}
+
+	deliver(sub, first)
+	deliver(sub, second)
+
+	got := <-sub.c
+	if got.Timestamp != second.Timestamp {
+		t.Errorf("deliver() under DropOldest kept %v, want the newest sample %v", got.Timestamp, second.Timestamp)
+	}
+	select {
+	case v := <-sub.c:
+		t.Errorf("deliver() under DropOldest left an extra buffered sample: %v", v.Timestamp)
+	default:
+	}
+}
+
+func TestFastestRateLocked(t *testing.T) {
+	s := &streamState{subs: map[uint64]*subscriber{
+		0: {opts: StreamOptions{Rate: 500 * time.Millisecond}},
+		1: {opts: StreamOptions{Rate: 50 * time.Millisecond}},
+		2: {opts: StreamOptions{Rate: 250 * time.Millisecond}},
+	}}
+	if got := s.fastestRateLocked(); got != 50*time.Millisecond {
+		t.Errorf("fastestRateLocked() = %v, want 50ms", got)
+	}
+}
+
+func TestFastestRateLockedNoSubscribers(t *testing.T) {
+	s := &streamState{subs: map[uint64]*subscriber{}}
+	if got := s.fastestRateLocked(); got != time.Hour {
+		t.Errorf("fastestRateLocked() with no subscribers = %v, want the 1h fallback", got)
+	}
+}
+
+func TestCloseOneDropOldest(t *testing.T) {
+	sub := newSubscriber(StreamOptions{Policy: DropOldest}, make(chan SensedEnv, 1))
+	closeOne(sub)
+	if _, ok := <-sub.c; ok {
+		t.Error("closeOne() on a DropOldest subscriber left c open")
+	}
+}
+
+// TestCloseOneBlockDrainsThenCloses exercises a Block subscriber's full
+// lifecycle: samples enqueued before a close request are still delivered by
+// the dedicated worker, and c only closes once the queue has drained,
+// matching Subscription.Close's documented contract.
+func TestCloseOneBlockDrainsThenCloses(t *testing.T) {
+	sub := newSubscriber(StreamOptions{Policy: Block}, make(chan SensedEnv))
+	sub.enqueue(SensedEnv{Timestamp: time.Unix(1, 0)})
+	sub.enqueue(SensedEnv{Timestamp: time.Unix(2, 0)})
+	closeOne(sub)
+
+	var got []time.Time
+	for v := range sub.c {
+		got = append(got, v.Timestamp)
+	}
+	if len(got) != 2 || got[0] != time.Unix(1, 0) || got[1] != time.Unix(2, 0) {
+		t.Errorf("range over a closed Block subscriber's channel = %v, want both queued samples in order", got)
+	}
+}
+
+// TestDeliverBlockNeverBlocks confirms that delivering to a stalled Block
+// subscriber (no reader draining c) returns immediately, since samples land
+// in the unbounded queue rather than being sent synchronously.
+func TestDeliverBlockNeverBlocks(t *testing.T) {
+	sub := newSubscriber(StreamOptions{Policy: Block}, make(chan SensedEnv))
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			deliver(sub, SensedEnv{Timestamp: time.Unix(int64(i), 0)})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("deliver() under Block blocked with no reader draining c")
+	}
+}